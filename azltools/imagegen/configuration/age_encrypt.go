@@ -0,0 +1,122 @@
+package configuration
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+)
+
+// encryptWithAge encrypts plaintext for recipients (X25519 public keys, SSH
+// public keys, or scrypt passphrases, one per entry) and writes the result to
+// path via fsys with 0400 permissions.
+func encryptWithAge(fsys WritableFS, path string, plaintext []byte, recipients []string) error {
+	ageRecipients, err := parseAgeRecipients(recipients)
+	if err != nil {
+		return fmt.Errorf("failed to parse age recipients: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, ageRecipients...)
+	if err != nil {
+		return fmt.Errorf("failed to start age encryption: %w", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return fmt.Errorf("failed to write age plaintext: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finalize age encryption: %w", err)
+	}
+
+	if err := fsys.WriteFile(path, buf.Bytes(), secretFilePerm); err != nil {
+		return fmt.Errorf("failed to write age-encrypted file: %w", err)
+	}
+	return nil
+}
+
+// parseAgeRecipients converts the recipient strings in TridentConfigData's
+// AgeRecipients (X25519 public keys, SSH public keys, or scrypt passphrases)
+// into age.Recipient values.
+func parseAgeRecipients(recipients []string) ([]age.Recipient, error) {
+	parsed := make([]age.Recipient, 0, len(recipients))
+	for _, r := range recipients {
+		recipient, err := parseAgeRecipient(r)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, recipient)
+	}
+	return parsed, nil
+}
+
+func parseAgeRecipient(r string) (age.Recipient, error) {
+	switch {
+	case strings.HasPrefix(r, "age1"):
+		return age.ParseX25519Recipient(r)
+	case strings.HasPrefix(r, "ssh-"):
+		return agessh.ParseRecipient(r)
+	default:
+		// Treat anything else as a scrypt passphrase.
+		return age.NewScryptRecipient(r)
+	}
+}
+
+// DecryptTridentHostConfig decrypts an age-encrypted host configuration file
+// (as written by RenderTridentHostConfig when TridentConfigData.AgeRecipients
+// is set) using identities, which may be X25519 identity strings
+// ("AGE-SECRET-KEY-..."), SSH private keys, or scrypt passphrases.
+func DecryptTridentHostConfig(path string, identities []string) ([]byte, error) {
+	ageIdentities, err := parseAgeIdentities(identities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age identities: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open age-encrypted file: %w", err)
+	}
+	defer f.Close()
+
+	r, err := age.Decrypt(f, ageIdentities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start age decryption: %w", err)
+	}
+
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read age plaintext: %w", err)
+	}
+	return plaintext, nil
+}
+
+func parseAgeIdentities(identities []string) ([]age.Identity, error) {
+	parsed := make([]age.Identity, 0, len(identities))
+	for _, id := range identities {
+		switch {
+		case strings.HasPrefix(id, "AGE-SECRET-KEY-"):
+			identity, err := age.ParseX25519Identity(id)
+			if err != nil {
+				return nil, err
+			}
+			parsed = append(parsed, identity)
+		case strings.Contains(id, "PRIVATE KEY"):
+			sshIdentity, err := agessh.ParseIdentity([]byte(id))
+			if err != nil {
+				return nil, err
+			}
+			parsed = append(parsed, sshIdentity)
+		default:
+			// Treat anything else as a scrypt passphrase.
+			identity, err := age.NewScryptIdentity(id)
+			if err != nil {
+				return nil, err
+			}
+			parsed = append(parsed, identity)
+		}
+	}
+	return parsed, nil
+}
@@ -0,0 +1,53 @@
+package configuration
+
+import (
+	"io"
+	"os"
+)
+
+// WritableFS abstracts the filesystem operations RenderTridentHostConfig
+// needs so rendering can be staged into a MemFS for tests instead of always
+// touching the real disk, and so a failure partway through can be simulated
+// deterministically.
+type WritableFS interface {
+	MkdirAll(path string, perm os.FileMode) error
+	MkdirTemp(dir, pattern string) (string, error)
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	Create(path string, perm os.FileMode) (io.WriteCloser, error)
+	ReadFile(path string) ([]byte, error)
+	Remove(path string) error
+	RemoveAll(path string) error
+	Rename(oldpath, newpath string) error
+	// Exists reports whether path names a file or directory.
+	Exists(path string) bool
+}
+
+// OSFS implements WritableFS against the real filesystem.
+type OSFS struct{}
+
+var _ WritableFS = OSFS{}
+
+func (OSFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+
+func (OSFS) MkdirTemp(dir, pattern string) (string, error) { return os.MkdirTemp(dir, pattern) }
+
+func (OSFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(path, data, perm)
+}
+
+func (OSFS) Create(path string, perm os.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+func (OSFS) ReadFile(path string) ([]byte, error) { return os.ReadFile(path) }
+
+func (OSFS) Remove(path string) error { return os.Remove(path) }
+
+func (OSFS) RemoveAll(path string) error { return os.RemoveAll(path) }
+
+func (OSFS) Rename(oldpath, newpath string) error { return os.Rename(oldpath, newpath) }
+
+func (OSFS) Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,24 @@
+package hash
+
+import "golang.org/x/crypto/bcrypt"
+
+// DefaultBcryptCost is used when Bcrypt.Cost is left at its zero value.
+const DefaultBcryptCost = 12
+
+// Bcrypt hashes passwords with golang.org/x/crypto/bcrypt.
+type Bcrypt struct {
+	// Cost is the bcrypt work factor. Zero selects DefaultBcryptCost.
+	Cost int
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	cost := b.Cost
+	if cost == 0 {
+		cost = DefaultBcryptCost
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
@@ -0,0 +1,9 @@
+// Package hash provides pluggable password hashing for artifacts (such as
+// the generated password script) that must not embed cleartext credentials.
+package hash
+
+// Hasher produces a hash string suitable for `usermod -p`, i.e. a complete
+// crypt(3)-style hash including its algorithm identifier.
+type Hasher interface {
+	Hash(password string) (string, error)
+}
@@ -0,0 +1,32 @@
+package kdf
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters follow the OWASP baseline recommendation (m=64MiB,
+// t=1, p=4) for interactive, server-side key derivation.
+const (
+	Argon2idTime    = 1
+	Argon2idMemory  = 64 * 1024 // KiB
+	Argon2idThreads = 4
+	Argon2idKeyLen  = 64
+	Argon2idSaltLen = 16
+)
+
+// Argon2id derives keys with golang.org/x/crypto/argon2's Argon2id variant.
+type Argon2id struct{}
+
+func (Argon2id) Name() string { return "argon2id" }
+
+func (Argon2id) Params() string {
+	return fmt.Sprintf("t=%d,m=%d,p=%d", Argon2idTime, Argon2idMemory, Argon2idThreads)
+}
+
+func (Argon2id) SaltSize() int { return Argon2idSaltLen }
+
+func (Argon2id) Derive(password, salt []byte) ([]byte, error) {
+	return argon2.IDKey(password, salt, Argon2idTime, Argon2idMemory, Argon2idThreads, Argon2idKeyLen), nil
+}
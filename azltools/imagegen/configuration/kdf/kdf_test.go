@@ -0,0 +1,88 @@
+package kdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDerivers(t *testing.T) {
+	derivers := []KeyDeriver{
+		PBKDF2{},
+		Scrypt{},
+		Argon2id{},
+	}
+
+	for _, d := range derivers {
+		t.Run(d.Name(), func(t *testing.T) {
+			salt := bytes.Repeat([]byte{0x42}, d.SaltSize())
+
+			key, err := d.Derive([]byte("correct-horse-battery-staple"), salt)
+			if err != nil {
+				t.Fatalf("Derive() error = %v", err)
+			}
+
+			header := Encode(d, salt, key)
+
+			parsed, err := Parse(header)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", header, err)
+			}
+			if parsed.Name != d.Name() {
+				t.Errorf("parsed.Name = %q, want %q", parsed.Name, d.Name())
+			}
+			if !bytes.Equal(parsed.Salt, salt) {
+				t.Errorf("parsed.Salt = %x, want %x", parsed.Salt, salt)
+			}
+			if !bytes.Equal(parsed.Key, key) {
+				t.Errorf("parsed.Key = %x, want %x", parsed.Key, key)
+			}
+
+			registered, err := ByName(d.Name())
+			if err != nil {
+				t.Fatalf("ByName(%q) error = %v", d.Name(), err)
+			}
+			rederived, err := registered.Derive([]byte("correct-horse-battery-staple"), parsed.Salt)
+			if err != nil {
+				t.Fatalf("re-Derive() error = %v", err)
+			}
+			if !bytes.Equal(rederived, parsed.Key) {
+				t.Errorf("re-derived key = %x, want %x", rederived, parsed.Key)
+			}
+		})
+	}
+}
+
+func TestByNameUnknown(t *testing.T) {
+	if _, err := ByName("not-a-real-kdf"); err == nil {
+		t.Fatal("ByName() with an unregistered name: expected an error")
+	}
+}
+
+func TestParseMalformedHeader(t *testing.T) {
+	tests := []string{
+		"",
+		"not a header at all",
+		"$scrypt$N=32768,r=8,p=1$onlyonefieldafterparams",
+		"$scrypt$N=32768,r=8,p=1$!!!notbase64!!!$!!!notbase64!!!",
+	}
+
+	for _, header := range tests {
+		t.Run(header, func(t *testing.T) {
+			if _, err := Parse(header); err == nil {
+				t.Errorf("Parse(%q): expected an error", header)
+			}
+		})
+	}
+}
+
+func TestIsHeader(t *testing.T) {
+	if !IsHeader([]byte("$scrypt$N=32768,r=8,p=1$c2FsdA==$a2V5")) {
+		t.Error("IsHeader() on an Encode-produced header: want true")
+	}
+	if IsHeader(make([]byte, 64)) {
+		t.Error("IsHeader() on a legacy raw binary key: want false")
+	}
+	if IsHeader(nil) {
+		t.Error("IsHeader(nil): want false")
+	}
+}
@@ -0,0 +1,32 @@
+package kdf
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Scrypt parameters match the project's baseline recommendation for
+// memory-hard recovery-key derivation.
+const (
+	ScryptN       = 1 << 15
+	ScryptR       = 8
+	ScryptP       = 1
+	ScryptKeyLen  = 64
+	ScryptSaltLen = 8
+)
+
+// Scrypt derives keys with golang.org/x/crypto/scrypt.
+type Scrypt struct{}
+
+func (Scrypt) Name() string { return "scrypt" }
+
+func (Scrypt) Params() string {
+	return fmt.Sprintf("N=%d,r=%d,p=%d", ScryptN, ScryptR, ScryptP)
+}
+
+func (Scrypt) SaltSize() int { return ScryptSaltLen }
+
+func (Scrypt) Derive(password, salt []byte) ([]byte, error) {
+	return scrypt.Key(password, salt, ScryptN, ScryptR, ScryptP, ScryptKeyLen)
+}
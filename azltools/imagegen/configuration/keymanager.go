@@ -0,0 +1,98 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// KeyManager wraps and unwraps key material using an external key management
+// service, so the master recovery key is never written to disk in plaintext.
+// Implementations are modeled after the cloud KMS envelope-encryption pattern:
+// Wrap encrypts plaintext with a key held by the service and returns the
+// ciphertext plus an opaque key version identifying which KMS key version
+// produced it; Unwrap reverses that given the same version.
+type KeyManager interface {
+	// Wrap encrypts plaintext with the manager's key and returns the
+	// ciphertext along with the key version used to produce it.
+	Wrap(ctx context.Context, plaintext []byte) (ciphertext []byte, keyVersion string, err error)
+	// Unwrap decrypts ciphertext that was produced by Wrap with keyVersion.
+	Unwrap(ctx context.Context, ciphertext []byte, keyVersion string) (plaintext []byte, err error)
+	// URI returns the configured KMS URI this manager was built from.
+	URI() string
+}
+
+// NewKeyManager parses a KMS URI and returns the matching KeyManager. fsys is
+// used by schemes that read local key material (currently file://) so tests
+// can supply a MemFS instead of touching the real disk. Supported schemes:
+//
+//	gcpkms://projects/p/locations/l/keyRings/r/cryptoKeys/k
+//	awskms://arn:aws:kms:region:account:key/key-id
+//	hashivault://vault.example.com/transit/keys/key-name
+//	file://path/to/local-wrapping-key (local dev and tests only)
+//	mem://anything (in-process, tests only; each call generates a fresh
+//	  wrapping key held only by the returned KeyManager, so ciphertext it
+//	  wraps cannot be unwrapped by any other KeyManager, including the one
+//	  UnwrapRecoveryKey builds)
+func NewKeyManager(fsys WritableFS, uri string) (KeyManager, error) {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok {
+		return nil, fmt.Errorf("configuration: invalid KMS URI %q: missing scheme", uri)
+	}
+
+	switch scheme {
+	case "gcpkms":
+		return newGCPKMSManager(uri, rest)
+	case "awskms":
+		return newAWSKMSManager(uri, rest)
+	case "hashivault":
+		return newVaultTransitManager(uri, rest)
+	case "file":
+		return newFileKeyManager(fsys, uri, rest)
+	case "mem":
+		km, err := NewInMemoryKeyManager()
+		if err != nil {
+			return nil, err
+		}
+		km.(*memKeyManager).uri = uri
+		return km, nil
+	default:
+		return nil, fmt.Errorf("configuration: unsupported KMS scheme %q", scheme)
+	}
+}
+
+// IsKMSURI reports whether encryptionKey names a KMS URI rather than a raw
+// passphrase, i.e. whether RenderTridentHostConfig should wrap a generated
+// master recovery key via NewKeyManager instead of deriving one via kdf.
+func IsKMSURI(encryptionKey string) bool {
+	for _, scheme := range []string{"gcpkms://", "awskms://", "hashivault://", "file://", "mem://"} {
+		if strings.HasPrefix(encryptionKey, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnwrapRecoveryKey reads a wrapped recovery key written by
+// RenderTridentHostConfig at path (ciphertext plus URI and key version) and
+// returns the unwrapped master recovery key. It always reads from the real
+// filesystem: like readWrappedKey, it is a post-hoc recovery helper invoked
+// against a committed Host Configuration directory, not part of the staged
+// render pipeline.
+func UnwrapRecoveryKey(ctx context.Context, path string) ([]byte, error) {
+	wrapped, err := readWrappedKey(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wrapped recovery key: %w", err)
+	}
+
+	km, err := NewKeyManager(OSFS{}, wrapped.URI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build key manager for %q: %w", wrapped.URI, err)
+	}
+
+	plaintext, err := km.Unwrap(ctx, wrapped.Ciphertext, wrapped.KeyVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap recovery key: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,66 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// awsKMSManager wraps and unwraps key material with AWS KMS. uri is the full
+// awskms:// URI and keyID is the ARN or key id that follows the scheme.
+type awsKMSManager struct {
+	uri   string
+	keyID string
+}
+
+func newAWSKMSManager(uri, keyID string) (KeyManager, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("configuration: awskms URI %q missing key id", uri)
+	}
+	return &awsKMSManager{uri: uri, keyID: keyID}, nil
+}
+
+func (m *awsKMSManager) URI() string { return m.uri }
+
+func (m *awsKMSManager) client(ctx context.Context) (*kms.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return kms.NewFromConfig(cfg), nil
+}
+
+func (m *awsKMSManager) Wrap(ctx context.Context, plaintext []byte) ([]byte, string, error) {
+	client, err := m.client(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	out, err := client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:     aws.String(m.keyID),
+		Plaintext: plaintext,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("AWS KMS encrypt failed: %w", err)
+	}
+	return out.CiphertextBlob, aws.ToString(out.KeyId), nil
+}
+
+func (m *awsKMSManager) Unwrap(ctx context.Context, ciphertext []byte, keyVersion string) ([]byte, error) {
+	client, err := m.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(keyVersion),
+		CiphertextBlob: ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AWS KMS decrypt failed: %w", err)
+	}
+	return out.Plaintext, nil
+}
@@ -0,0 +1,69 @@
+package configuration
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+// fileKeyManager wraps and unwraps key material with AES-256-GCM using a
+// 32-byte wrapping key read from fsys at path. It exists for tests and local
+// development so callers can exercise the KeyManager code paths without a
+// real cloud KMS or Vault cluster; it is not a substitute for a managed KMS
+// in production.
+type fileKeyManager struct {
+	fsys WritableFS
+	uri  string
+	path string
+}
+
+func newFileKeyManager(fsys WritableFS, uri, path string) (KeyManager, error) {
+	if path == "" {
+		return nil, fmt.Errorf("configuration: file URI %q missing wrapping key path", uri)
+	}
+	return &fileKeyManager{fsys: fsys, uri: uri, path: path}, nil
+}
+
+func (m *fileKeyManager) URI() string { return m.uri }
+
+func (m *fileKeyManager) aead() (cipher.AEAD, error) {
+	key, err := m.fsys.ReadFile(m.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wrapping key %q: %w", m.path, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("wrapping key %q must be 32 bytes, got %d", m.path, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func (m *fileKeyManager) Wrap(_ context.Context, plaintext []byte) ([]byte, string, error) {
+	aead, err := m.aead()
+	if err != nil {
+		return nil, "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	ciphertext := aead.Seal(nonce, nonce, plaintext, nil)
+	return ciphertext, "v1", nil
+}
+
+func (m *fileKeyManager) Unwrap(_ context.Context, ciphertext []byte, _ string) ([]byte, error) {
+	aead, err := m.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, nil)
+}
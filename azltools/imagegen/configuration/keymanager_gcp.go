@@ -0,0 +1,61 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	kmspb "cloud.google.com/go/kms/apiv1/kmspb"
+)
+
+// gcpKMSManager wraps and unwraps key material with Google Cloud KMS. uri is
+// the full gcpkms:// URI and keyName is the resource path that follows the
+// scheme (projects/.../cryptoKeys/...).
+type gcpKMSManager struct {
+	uri     string
+	keyName string
+}
+
+func newGCPKMSManager(uri, keyName string) (KeyManager, error) {
+	if keyName == "" {
+		return nil, fmt.Errorf("configuration: gcpkms URI %q missing key resource path", uri)
+	}
+	return &gcpKMSManager{uri: uri, keyName: keyName}, nil
+}
+
+func (m *gcpKMSManager) URI() string { return m.uri }
+
+func (m *gcpKMSManager) Wrap(ctx context.Context, plaintext []byte) ([]byte, string, error) {
+	client, err := kms.NewKeyManagementClient(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create GCP KMS client: %w", err)
+	}
+	defer client.Close()
+
+	resp, err := client.Encrypt(ctx, &kmspb.EncryptRequest{
+		Name:      m.keyName,
+		Plaintext: plaintext,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("GCP KMS encrypt failed: %w", err)
+	}
+	return resp.Ciphertext, resp.Name, nil
+}
+
+func (m *gcpKMSManager) Unwrap(ctx context.Context, ciphertext []byte, keyVersion string) ([]byte, error) {
+	client, err := kms.NewKeyManagementClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCP KMS client: %w", err)
+	}
+	defer client.Close()
+
+	resp, err := client.Decrypt(ctx, &kmspb.DecryptRequest{
+		Name:       m.keyName,
+		Ciphertext: ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GCP KMS decrypt failed: %w", err)
+	}
+	_ = keyVersion // GCP KMS resolves the version from the ciphertext itself.
+	return resp.Plaintext, nil
+}
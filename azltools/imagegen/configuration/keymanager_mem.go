@@ -0,0 +1,58 @@
+package configuration
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"sync/atomic"
+)
+
+// memKeyManager is an in-process KeyManager backed by a randomly generated
+// AES-256-GCM key. It is intended for tests that need to exercise
+// RenderTridentHostConfig's KMS-wrapping path without a file, a cloud
+// account, or a Vault cluster.
+type memKeyManager struct {
+	uri     string
+	aead    cipher.AEAD
+	version int64
+}
+
+// NewInMemoryKeyManager returns a KeyManager backed by a freshly generated
+// in-process key, for use in tests.
+func NewInMemoryKeyManager() (KeyManager, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate in-memory KMS key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &memKeyManager{uri: "mem://in-memory", aead: aead}, nil
+}
+
+func (m *memKeyManager) URI() string { return m.uri }
+
+func (m *memKeyManager) Wrap(_ context.Context, plaintext []byte) ([]byte, string, error) {
+	nonce := make([]byte, m.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	ciphertext := m.aead.Seal(nonce, nonce, plaintext, nil)
+	version := atomic.AddInt64(&m.version, 1)
+	return ciphertext, fmt.Sprintf("v%d", version), nil
+}
+
+func (m *memKeyManager) Unwrap(_ context.Context, ciphertext []byte, _ string) ([]byte, error) {
+	if len(ciphertext) < m.aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:m.aead.NonceSize()], ciphertext[m.aead.NonceSize():]
+	return m.aead.Open(nil, nonce, sealed, nil)
+}
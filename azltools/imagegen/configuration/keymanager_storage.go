@@ -0,0 +1,41 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// wrappedKey is the on-disk representation of a KMS-wrapped recovery key:
+// enough to reconstruct the same KeyManager and unwrap the ciphertext without
+// any other state.
+type wrappedKey struct {
+	URI        string `json:"uri"`
+	KeyVersion string `json:"key_version"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func writeWrappedKey(fsys WritableFS, path string, w wrappedKey) error {
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal wrapped recovery key: %w", err)
+	}
+	if err := fsys.WriteFile(path, data, secretFilePerm); err != nil {
+		return fmt.Errorf("failed to write wrapped recovery key: %w", err)
+	}
+	return nil
+}
+
+// readWrappedKey always reads from the real filesystem: UnwrapRecoveryKey is
+// a post-hoc recovery helper invoked against a committed Host Configuration
+// directory, not part of the staged render pipeline.
+func readWrappedKey(path string) (*wrappedKey, error) {
+	data, err := OSFS{}.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var w wrappedKey
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal wrapped recovery key: %w", err)
+	}
+	return &w, nil
+}
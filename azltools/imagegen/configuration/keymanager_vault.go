@@ -0,0 +1,100 @@
+package configuration
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// vaultTransitManager wraps and unwraps key material with HashiCorp Vault's
+// Transit secrets engine. uri is the full hashivault:// URI; addr and
+// keyName are parsed from the host and transit key path that follow the
+// scheme (host/transit/keys/key-name).
+type vaultTransitManager struct {
+	uri     string
+	addr    string
+	keyName string
+}
+
+func newVaultTransitManager(uri, rest string) (KeyManager, error) {
+	host, keyPath, ok := splitFirstSlash(rest)
+	if !ok {
+		return nil, fmt.Errorf("configuration: hashivault URI %q missing transit key path", uri)
+	}
+	keyName := vaultKeyNameFromPath(keyPath)
+	if keyName == "" {
+		return nil, fmt.Errorf("configuration: hashivault URI %q missing key name", uri)
+	}
+	return &vaultTransitManager{uri: uri, addr: "https://" + host, keyName: keyName}, nil
+}
+
+func splitFirstSlash(s string) (before, after string, ok bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '/' {
+			return s[:i], s[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+func vaultKeyNameFromPath(p string) string {
+	// Expect "transit/keys/<name>".
+	const prefix = "transit/keys/"
+	if len(p) <= len(prefix) || p[:len(prefix)] != prefix {
+		return ""
+	}
+	return p[len(prefix):]
+}
+
+func (m *vaultTransitManager) URI() string { return m.uri }
+
+func (m *vaultTransitManager) client() (*vault.Client, error) {
+	cfg := vault.DefaultConfig()
+	cfg.Address = m.addr
+	return vault.NewClient(cfg)
+}
+
+func (m *vaultTransitManager) Wrap(ctx context.Context, plaintext []byte) ([]byte, string, error) {
+	client, err := m.client()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create Vault client: %w", err)
+	}
+
+	secret, err := client.Logical().WriteWithContext(ctx, fmt.Sprintf("transit/encrypt/%s", m.keyName), map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("Vault transit encrypt failed: %w", err)
+	}
+
+	ciphertext, _ := secret.Data["ciphertext"].(string)
+	// Vault decodes key_version as a JSON number, not a string.
+	var keyVersion string
+	if v, ok := secret.Data["key_version"]; ok && v != nil {
+		keyVersion = fmt.Sprint(v)
+	}
+	return []byte(ciphertext), keyVersion, nil
+}
+
+func (m *vaultTransitManager) Unwrap(ctx context.Context, ciphertext []byte, keyVersion string) ([]byte, error) {
+	client, err := m.client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vault client: %w", err)
+	}
+
+	secret, err := client.Logical().WriteWithContext(ctx, fmt.Sprintf("transit/decrypt/%s", m.keyName), map[string]interface{}{
+		"ciphertext": string(ciphertext),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Vault transit decrypt failed: %w", err)
+	}
+
+	plaintextB64, _ := secret.Data["plaintext"].(string)
+	plaintext, err := base64.StdEncoding.DecodeString(plaintextB64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Vault transit plaintext: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,221 @@
+package configuration
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// MemFS is an in-memory WritableFS for tests. FailOn, if set, maps an exact
+// path to the error an operation on that path should return, so tests can
+// exercise the staging/rollback behaviour of RenderTridentHostConfig when a
+// write fails partway through.
+type MemFS struct {
+	FailOn map[string]error
+
+	mu     sync.Mutex
+	files  map[string][]byte
+	dirs   map[string]bool
+	tmpSeq int
+}
+
+var _ WritableFS = (*MemFS)(nil)
+
+// NewMemFS returns an empty MemFS ready for use.
+func NewMemFS() *MemFS {
+	return &MemFS{
+		files: make(map[string][]byte),
+		dirs:  make(map[string]bool),
+	}
+}
+
+func (m *MemFS) failure(path string) error {
+	if m.FailOn == nil {
+		return nil
+	}
+	return m.FailOn[path]
+}
+
+func (m *MemFS) MkdirAll(path string, _ os.FileMode) error {
+	if err := m.failure(path); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dirs[path] = true
+	return nil
+}
+
+func (m *MemFS) MkdirTemp(dir, pattern string) (string, error) {
+	if err := m.failure(dir); err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tmpSeq++
+	path := fmt.Sprintf("%s/%s%d", strings.TrimSuffix(dir, "/"), strings.TrimSuffix(pattern, "*"), m.tmpSeq)
+	m.dirs[path] = true
+	return path, nil
+}
+
+func (m *MemFS) WriteFile(path string, data []byte, _ os.FileMode) error {
+	if err := m.failure(path); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := make([]byte, len(data))
+	copy(cp, data)
+	m.files[path] = cp
+	return nil
+}
+
+// memFile buffers writes and only commits them to the MemFS on Close, like
+// os.Create followed by a real file descriptor's buffered writes landing on
+// close.
+type memFile struct {
+	buf  bytes.Buffer
+	fs   *MemFS
+	path string
+}
+
+func (f *memFile) Write(p []byte) (int, error) { return f.buf.Write(p) }
+
+func (f *memFile) Close() error { return f.fs.WriteFile(f.path, f.buf.Bytes(), 0) }
+
+func (m *MemFS) Create(path string, _ os.FileMode) (io.WriteCloser, error) {
+	if err := m.failure(path); err != nil {
+		return nil, err
+	}
+	return &memFile{fs: m, path: path}, nil
+}
+
+func (m *MemFS) ReadFile(path string) ([]byte, error) {
+	if err := m.failure(path); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[path]
+	if !ok {
+		return nil, fmt.Errorf("memfs: no such file %q", path)
+	}
+	return data, nil
+}
+
+func (m *MemFS) Remove(path string) error {
+	if err := m.failure(path); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, path)
+	return nil
+}
+
+func (m *MemFS) RemoveAll(path string) error {
+	if err := m.failure(path); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prefix := path + "/"
+	for p := range m.files {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(m.files, p)
+		}
+	}
+	for d := range m.dirs {
+		if d == path || strings.HasPrefix(d, prefix) {
+			delete(m.dirs, d)
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	if err := m.failure(oldpath); err != nil {
+		return err
+	}
+	if err := m.failure(newpath); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.existsLocked(oldpath) {
+		return fmt.Errorf("memfs: rename %s %s: no such file or directory", oldpath, newpath)
+	}
+
+	oldPrefix := oldpath + "/"
+
+	movedFiles := make(map[string][]byte)
+	for p, data := range m.files {
+		switch {
+		case p == oldpath:
+			movedFiles[newpath] = data
+		case strings.HasPrefix(p, oldPrefix):
+			movedFiles[newpath+"/"+strings.TrimPrefix(p, oldPrefix)] = data
+		}
+	}
+	for p := range m.files {
+		if p == oldpath || strings.HasPrefix(p, oldPrefix) {
+			delete(m.files, p)
+		}
+	}
+	for p, data := range movedFiles {
+		m.files[p] = data
+	}
+
+	movedDirs := make(map[string]bool)
+	for d := range m.dirs {
+		switch {
+		case d == oldpath:
+			movedDirs[newpath] = true
+		case strings.HasPrefix(d, oldPrefix):
+			movedDirs[newpath+"/"+strings.TrimPrefix(d, oldPrefix)] = true
+		}
+	}
+	for d := range m.dirs {
+		if d == oldpath || strings.HasPrefix(d, oldPrefix) {
+			delete(m.dirs, d)
+		}
+	}
+	for d := range movedDirs {
+		m.dirs[d] = true
+	}
+
+	return nil
+}
+
+// Exists reports whether path names a file, a directory, or the ancestor of
+// any tracked file or directory.
+func (m *MemFS) Exists(path string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.existsLocked(path)
+}
+
+func (m *MemFS) existsLocked(path string) bool {
+	if _, ok := m.files[path]; ok {
+		return true
+	}
+	if m.dirs[path] {
+		return true
+	}
+	prefix := path + "/"
+	for p := range m.files {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	for d := range m.dirs {
+		if strings.HasPrefix(d, prefix) {
+			return true
+		}
+	}
+	return false
+}
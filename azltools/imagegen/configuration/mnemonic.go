@@ -0,0 +1,92 @@
+package configuration
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39/wordlists"
+)
+
+// bip39WordBits is the number of bits of entropy each BIP39 word encodes.
+const bip39WordBits = 11
+
+// recoveryKeyToMnemonic encodes entropy (the 64-byte master recovery key) as
+// a BIP39 mnemonic using the standard English word list: entropy bit-length
+// ENT must be a multiple of 32, the checksum is the first ENT/32 bits of
+// SHA-256(entropy), and the (ENT+CS)-bit concatenation of entropy and
+// checksum is split into 11-bit groups, each indexing one word. 64 bytes of
+// entropy (ENT=512, CS=16) yields 48 words.
+func recoveryKeyToMnemonic(entropy []byte) ([]string, error) {
+	entBits := len(entropy) * 8
+	if entBits%32 != 0 {
+		return nil, fmt.Errorf("mnemonic: entropy length %d bits is not a multiple of 32", entBits)
+	}
+	checksumBits := entBits / 32
+
+	hash := sha256.Sum256(entropy)
+
+	// Concatenate entropy and the leading checksumBits of its SHA-256 hash.
+	bits := new(big.Int).SetBytes(entropy)
+	bits.Lsh(bits, uint(checksumBits))
+	checksum := new(big.Int).SetBytes(hash[:])
+	checksum.Rsh(checksum, uint(256-checksumBits))
+	bits.Or(bits, checksum)
+
+	totalBits := entBits + checksumBits
+	wordCount := totalBits / bip39WordBits
+
+	mask := big.NewInt(1<<bip39WordBits - 1)
+	words := make([]string, wordCount)
+	for i := wordCount - 1; i >= 0; i-- {
+		index := new(big.Int).And(bits, mask).Int64()
+		words[i] = wordlists.English[index]
+		bits.Rsh(bits, bip39WordBits)
+	}
+	return words, nil
+}
+
+// RecoverKeyFromMnemonic reverses recoveryKeyToMnemonic, validating the
+// embedded checksum and returning the original entropy (the master recovery
+// key).
+func RecoverKeyFromMnemonic(words []string) ([]byte, error) {
+	index := make(map[string]int64, len(wordlists.English))
+	for i, w := range wordlists.English {
+		index[w] = int64(i)
+	}
+
+	totalBits := len(words) * bip39WordBits
+	entBits := totalBits * 32 / 33
+	if entBits%32 != 0 || entBits+entBits/32 != totalBits {
+		return nil, fmt.Errorf("mnemonic: %d words is not a valid BIP39 length", len(words))
+	}
+	checksumBits := entBits / 32
+
+	bits := new(big.Int)
+	for _, w := range words {
+		i, ok := index[strings.ToLower(w)]
+		if !ok {
+			return nil, fmt.Errorf("mnemonic: unknown word %q", w)
+		}
+		bits.Lsh(bits, bip39WordBits)
+		bits.Or(bits, big.NewInt(i))
+	}
+
+	checksumMask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(checksumBits)), big.NewInt(1))
+	gotChecksum := new(big.Int).And(bits, checksumMask)
+
+	entropy := new(big.Int).Rsh(bits, uint(checksumBits))
+	entropyBytes := make([]byte, entBits/8)
+	entropy.FillBytes(entropyBytes)
+
+	hash := sha256.Sum256(entropyBytes)
+	wantChecksum := new(big.Int).SetBytes(hash[:])
+	wantChecksum.Rsh(wantChecksum, uint(256-checksumBits))
+
+	if gotChecksum.Cmp(wantChecksum) != 0 {
+		return nil, fmt.Errorf("mnemonic: checksum mismatch")
+	}
+
+	return entropyBytes, nil
+}
@@ -0,0 +1,104 @@
+package configuration
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{name: "single quote", in: `it's a test`},
+		{name: "backslash", in: `C:\path\to\thing`},
+		{name: "newline", in: "line one\nline two"},
+		{name: "quote, backslash and newline together", in: "it's\\nasty'\n$(rm -rf /)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quoted := shellQuote(tt.in)
+
+			if !strings.HasPrefix(quoted, "'") || !strings.HasSuffix(quoted, "'") {
+				t.Fatalf("shellQuote(%q) = %q, want a string wrapped in single quotes", tt.in, quoted)
+			}
+
+			// Every single quote in the input must have been closed out of
+			// the quoted string and escaped, never left bare: a bare quote
+			// would end the shell's quoting early and let the rest of the
+			// string be interpreted as shell syntax.
+			inner := quoted[1 : len(quoted)-1]
+			if strings.Contains(inner, "'") && !strings.Contains(quoted, `'\''`) {
+				t.Fatalf("shellQuote(%q) = %q, contains an unescaped single quote", tt.in, quoted)
+			}
+		})
+	}
+}
+
+func TestPasswordScriptEscapesDangerousPasswords(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+	}{
+		{name: "single quote", password: `p'ssword`},
+		{name: "backslash", password: `pa\ssword`},
+		{name: "newline", password: "pass\nword"},
+		{name: "shell metacharacters", password: `'; rm -rf / #`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS()
+			configData := &TridentConfigData{
+				Username: "testuser",
+				Password: tt.password,
+			}
+
+			path := "/scripts/user-password.sh"
+			if err := passwordScript(fsys, path, configData); err != nil {
+				t.Fatalf("passwordScript() error = %v", err)
+			}
+
+			script, err := fsys.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read generated script: %v", err)
+			}
+
+			// The password is hashed before it ever reaches the script, so
+			// the cleartext password - quotes, backslashes, newlines and
+			// all - must never appear in the output.
+			if strings.Contains(string(script), tt.password) {
+				t.Errorf("password script contains the cleartext password: %q", script)
+			}
+
+			if !strings.HasPrefix(string(script), "usermod -p '") {
+				t.Errorf("password script = %q, want a usermod -p invocation with a quoted hash", script)
+			}
+		})
+	}
+}
+
+func TestPasswordScriptRejectsInvalidUsername(t *testing.T) {
+	tests := []string{
+		"",
+		"Administrator",
+		"user'; rm -rf / #",
+		"user name",
+		"-user",
+	}
+
+	for _, username := range tests {
+		t.Run(username, func(t *testing.T) {
+			fsys := NewMemFS()
+			configData := &TridentConfigData{
+				Username: username,
+				Password: "hunter2",
+			}
+
+			if err := passwordScript(fsys, "/scripts/user-password.sh", configData); err == nil {
+				t.Errorf("passwordScript() with username %q: expected an error", username)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package configuration
+
+import "os"
+
+// Filesystem permissions used throughout host configuration rendering,
+// defined once so every write site agrees on which artifacts are secrets.
+const (
+	configDirPerm  os.FileMode = 0755
+	scriptsDirPerm os.FileMode = 0700
+	scriptFilePerm os.FileMode = 0700
+	secretFilePerm os.FileMode = 0400
+	configFilePerm os.FileMode = 0644
+)
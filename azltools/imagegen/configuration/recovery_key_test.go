@@ -0,0 +1,78 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"trident/azltools/imagegen/configuration/kdf"
+)
+
+func TestVerifyRecoveryKeyCurrentHeader(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "recovery.key")
+
+	fsys := NewMemFS()
+	if _, err := generateRecoveryKeyFromPassword(fsys, keyPath, "correct-horse-battery-staple", kdf.Default); err != nil {
+		t.Fatalf("generateRecoveryKeyFromPassword() error = %v", err)
+	}
+	header, err := fsys.ReadFile(keyPath)
+	if err != nil {
+		t.Fatalf("failed to read generated header: %v", err)
+	}
+	if err := os.WriteFile(keyPath, header, secretFilePerm); err != nil {
+		t.Fatalf("failed to seed recovery key on disk: %v", err)
+	}
+
+	ok, err := VerifyRecoveryKey(keyPath, "correct-horse-battery-staple")
+	if err != nil {
+		t.Fatalf("VerifyRecoveryKey() error = %v", err)
+	}
+	if !ok {
+		t.Error("VerifyRecoveryKey() with the correct password: want true")
+	}
+
+	ok, err = VerifyRecoveryKey(keyPath, "wrong password")
+	if err != nil {
+		t.Fatalf("VerifyRecoveryKey() error = %v", err)
+	}
+	if ok {
+		t.Error("VerifyRecoveryKey() with the wrong password: want false")
+	}
+}
+
+func TestVerifyRecoveryKeyLegacyFormat(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "recovery.key")
+
+	legacy := legacyDeriveRecoveryKey("correct-horse-battery-staple")
+	if err := os.WriteFile(keyPath, legacy, secretFilePerm); err != nil {
+		t.Fatalf("failed to seed legacy recovery key: %v", err)
+	}
+
+	ok, err := VerifyRecoveryKey(keyPath, "correct-horse-battery-staple")
+	if err != nil {
+		t.Fatalf("VerifyRecoveryKey() error = %v", err)
+	}
+	if !ok {
+		t.Error("VerifyRecoveryKey() on a legacy raw key with the correct password: want true")
+	}
+
+	ok, err = VerifyRecoveryKey(keyPath, "wrong password")
+	if err != nil {
+		t.Fatalf("VerifyRecoveryKey() error = %v", err)
+	}
+	if ok {
+		t.Error("VerifyRecoveryKey() on a legacy raw key with the wrong password: want false")
+	}
+}
+
+func TestVerifyRecoveryKeyMalformedHeader(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "recovery.key")
+
+	if err := os.WriteFile(keyPath, []byte("$scrypt$N=32768,r=8,p=1$not-a-valid-header"), secretFilePerm); err != nil {
+		t.Fatalf("failed to seed malformed recovery key: %v", err)
+	}
+
+	if _, err := VerifyRecoveryKey(keyPath, "correct-horse-battery-staple"); err == nil {
+		t.Error("VerifyRecoveryKey() on a malformed header: expected an error, not a false match")
+	}
+}
@@ -1,12 +1,20 @@
 package configuration
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	_ "embed"
 	"fmt"
-	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"text/template"
+
+	"trident/azltools/imagegen/configuration/hash"
+	"trident/azltools/imagegen/configuration/kdf"
 )
 
 //go:embed template/host-config.yaml.tmpl
@@ -17,46 +25,142 @@ var hostConfigEncryptedTemplate string
 
 // Creates Host Configuration in the specified path, by adding the user input to the template.
 func RenderTridentHostConfig(configPath string, configData *TridentConfigData) error {
+	return renderTridentHostConfig(OSFS{}, configPath, configData)
+}
+
+// renderTridentHostConfig stages every artifact (scripts directory, password
+// script, recovery key, rendered template) into a temporary directory next
+// to configDir, and only replaces configDir with the staged directory once
+// every step has succeeded. This keeps a partial failure (e.g. the password
+// script writes but the template fails to parse) from leaving behind a
+// half-provisioned config directory: on error the staging directory is
+// removed and configDir is untouched.
+//
+// The final swap itself moves any existing configDir aside rather than
+// removing it outright. Between the two renames nothing is present at
+// configDir, but a complete directory always exists under either configDir
+// or the backup name: if the process is interrupted after the staging
+// directory lands but before the old one is cleaned up, the old directory
+// can still be recovered from its backup name.
+func renderTridentHostConfig(fsys WritableFS, configPath string, configData *TridentConfigData) (err error) {
 	configDir := filepath.Dir(configPath)
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	configFileName := filepath.Base(configPath)
+	parentDir := filepath.Dir(configDir)
+
+	if err = fsys.MkdirAll(parentDir, configDirPerm); err != nil {
 		return fmt.Errorf("failed to create Host Configuration directory: %w", err)
 	}
 
-	// Create scripts directory inside config directory
-	scriptsDir := filepath.Join(configDir, "scripts")
-	if err := os.MkdirAll(scriptsDir, 0700); err != nil {
+	stagingDir, err := fsys.MkdirTemp(parentDir, filepath.Base(configDir)+".staging-*")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = fsys.RemoveAll(stagingDir)
+		}
+	}()
+
+	if err = renderHostConfigArtifacts(fsys, stagingDir, configDir, configFileName, configData); err != nil {
+		return err
+	}
+
+	backupDir := stagingDir + ".previous"
+	hadExisting := fsys.Exists(configDir)
+	if hadExisting {
+		if err = fsys.Rename(configDir, backupDir); err != nil {
+			return fmt.Errorf("failed to move aside existing Host Configuration directory: %w", err)
+		}
+	}
+
+	if err = fsys.Rename(stagingDir, configDir); err != nil {
+		if hadExisting {
+			_ = fsys.Rename(backupDir, configDir)
+		}
+		return fmt.Errorf("failed to commit Host Configuration directory: %w", err)
+	}
+
+	if hadExisting {
+		if err = fsys.RemoveAll(backupDir); err != nil {
+			return fmt.Errorf("failed to remove previous Host Configuration directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// renderHostConfigArtifacts writes every artifact under stagingDir (as if it
+// were configDir) and sets configData.PasswordScript / RecoveryKeyPath to
+// where each artifact will live once stagingDir is renamed to configDir, so
+// the rendered template references its final location rather than the
+// staging path.
+func renderHostConfigArtifacts(fsys WritableFS, stagingDir, configDir, configFileName string, configData *TridentConfigData) error {
+	scriptsDir := filepath.Join(stagingDir, "scripts")
+	if err := fsys.MkdirAll(scriptsDir, scriptsDirPerm); err != nil {
 		return fmt.Errorf("failed to create scripts directory: %w", err)
 	}
 
-	// Write password script
 	passwordScriptPath := filepath.Join(scriptsDir, "user-password.sh")
-	err := passwordScript(passwordScriptPath, configData)
-	if err != nil {
+	if err := passwordScript(fsys, passwordScriptPath, configData); err != nil {
 		return fmt.Errorf("failed to write password script: %w", err)
 	}
-	configData.PasswordScript = passwordScriptPath
+	configData.PasswordScript = finalPath(stagingDir, configDir, passwordScriptPath)
 
 	// Select template
 	var templateContent string
-	if configData.EncryptionKey != "" {
+	switch {
+	case IsKMSURI(configData.EncryptionKey):
+		// The master recovery key never touches disk in plaintext: generate
+		// it, wrap it with the configured KMS, and persist only the
+		// ciphertext.
+		wrappedKeyPath := filepath.Join(stagingDir, "recovery.key.wrapped")
+		masterKey, err := generateAndWrapRecoveryKey(fsys, wrappedKeyPath, configData.EncryptionKey)
+		if err != nil {
+			return fmt.Errorf("failed to wrap recovery key: %w", err)
+		}
+		configData.RecoveryKeyPath = finalPath(stagingDir, configDir, wrappedKeyPath)
+		if configData.RecoveryKeyMnemonic {
+			if err := writeRecoveryKeyMnemonic(fsys, stagingDir, masterKey); err != nil {
+				return fmt.Errorf("failed to write recovery key mnemonic: %w", err)
+			}
+		}
+		templateContent = hostConfigEncryptedTemplate
+	case configData.EncryptionKey != "":
 		// Generate recovery key from encryption key
-		recoveryKeyPath := filepath.Join(configDir, "recovery.key")
-		err := generateRecoveryKeyFromPassword(recoveryKeyPath, configData.EncryptionKey)
+		deriver := kdf.Default
+		if configData.KDF != "" {
+			d, err := kdf.ByName(configData.KDF)
+			if err != nil {
+				return fmt.Errorf("invalid KDF: %w", err)
+			}
+			deriver = d
+		}
+		recoveryKeyPath := filepath.Join(stagingDir, "recovery.key")
+		derivedKey, err := generateRecoveryKeyFromPassword(fsys, recoveryKeyPath, configData.EncryptionKey, deriver)
 		if err != nil {
 			return fmt.Errorf("failed to generate recovery key: %w", err)
 		}
-		configData.RecoveryKeyPath = recoveryKeyPath
+		if configData.RecoveryKeyMnemonic {
+			if err := writeRecoveryKeyMnemonic(fsys, stagingDir, derivedKey); err != nil {
+				return fmt.Errorf("failed to write recovery key mnemonic: %w", err)
+			}
+		}
+		configData.RecoveryKeyPath = finalPath(stagingDir, configDir, recoveryKeyPath)
 		templateContent = hostConfigEncryptedTemplate
-	} else {
+	default:
 		templateContent = hostConfigTemplate
 	}
 
-	// Render the config file
 	tmpl, err := template.New("host-config").Parse(templateContent)
 	if err != nil {
 		return fmt.Errorf("failed to parse template: %w", err)
 	}
-	out, err := os.Create(configPath)
+
+	configPath := filepath.Join(stagingDir, configFileName)
+	if len(configData.AgeRecipients) > 0 {
+		return renderAgeEncrypted(fsys, configPath, passwordScriptPath, tmpl, configData)
+	}
+
+	out, err := fsys.Create(configPath, configFilePerm)
 	if err != nil {
 		return fmt.Errorf("failed to create config file: %w", err)
 	}
@@ -64,31 +168,204 @@ func RenderTridentHostConfig(configPath string, configData *TridentConfigData) e
 	return tmpl.Execute(out, configData)
 }
 
-// Creates the password script at the given path
-func passwordScript(passwordScriptPath string, configData *TridentConfigData) (err error) {
-	script := fmt.Sprintf("echo '%s:%s' | chpasswd\n", configData.Username, configData.Password)
-	dir := filepath.Dir(passwordScriptPath)
-	if err = os.MkdirAll(dir, 0700); err != nil {
+// finalPath rewrites a path rooted at stagingDir to the equivalent path
+// rooted at configDir, the directory stagingDir will become once rendering
+// completes successfully.
+func finalPath(stagingDir, configDir, path string) string {
+	return configDir + strings.TrimPrefix(path, stagingDir)
+}
+
+// renderAgeEncrypted renders tmpl and the already-written password script to
+// age-encrypted sidecars (configPath+".age", passwordScriptPath+".age"), then
+// removes the cleartext password script written by passwordScript, so
+// neither is left in cleartext in the staged directory.
+func renderAgeEncrypted(fsys WritableFS, configPath, passwordScriptPath string, tmpl *template.Template, configData *TridentConfigData) error {
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, configData); err != nil {
+		return fmt.Errorf("failed to render host config: %w", err)
+	}
+	if err := encryptWithAge(fsys, configPath+".age", rendered.Bytes(), configData.AgeRecipients); err != nil {
+		return fmt.Errorf("failed to age-encrypt host config: %w", err)
+	}
+
+	passwordScriptContent, err := fsys.ReadFile(passwordScriptPath)
+	if err != nil {
+		return fmt.Errorf("failed to read password script for encryption: %w", err)
+	}
+	if err := encryptWithAge(fsys, passwordScriptPath+".age", passwordScriptContent, configData.AgeRecipients); err != nil {
+		return fmt.Errorf("failed to age-encrypt password script: %w", err)
+	}
+	if err := fsys.Remove(passwordScriptPath); err != nil {
+		return fmt.Errorf("failed to remove cleartext password script: %w", err)
+	}
+
+	return nil
+}
+
+// usernameRegexp matches the usernames passwordScript is willing to emit into
+// the generated shell script.
+var usernameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+
+// Creates the password script at the given path. The password is hashed with
+// configData.PasswordHasher (defaulting to bcrypt cost 12) rather than
+// embedded in cleartext, and both the hash and the username are shell-quoted
+// so quotes, backslashes or newlines in either cannot break or inject into
+// the script.
+func passwordScript(fsys WritableFS, passwordScriptPath string, configData *TridentConfigData) (err error) {
+	if !usernameRegexp.MatchString(configData.Username) {
+		return fmt.Errorf("invalid username %q: must match %s", configData.Username, usernameRegexp.String())
+	}
+
+	hasher := configData.PasswordHasher
+	if hasher == nil {
+		hasher = hash.Bcrypt{}
+	}
+	hashedPassword, err := hasher.Hash(configData.Password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	script := fmt.Sprintf("usermod -p %s %s\n", shellQuote(hashedPassword), shellQuote(configData.Username))
+	if err = fsys.MkdirAll(filepath.Dir(passwordScriptPath), scriptsDirPerm); err != nil {
 		return
 	}
-	if err = os.WriteFile(passwordScriptPath, []byte(script), 0700); err != nil {
+	if err = fsys.WriteFile(passwordScriptPath, []byte(script), scriptFilePerm); err != nil {
 		return
 	}
 	return
 }
 
-// Generates a recovery key using a password
-func generateRecoveryKeyFromPassword(keyPath, password string) error {
-	// Use a simple but deterministic key derivation
+// shellQuote wraps s in single quotes, escaping any single quote it contains,
+// so the result is safe to splice into a shell command regardless of what
+// characters s holds.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Master recovery keys wrapped by a KeyManager are this long, matching the
+// length of keys RenderTridentHostConfig derives from a password via the kdf
+// package.
+const masterRecoveryKeyLength = 64
+
+// generateAndWrapRecoveryKey generates a random master recovery key, wraps it
+// with the KeyManager named by encryptionKey (a KMS URI), and writes the
+// ciphertext plus the URI and key version to wrappedKeyPath so
+// UnwrapRecoveryKey can reverse it later.
+func generateAndWrapRecoveryKey(fsys WritableFS, wrappedKeyPath, encryptionKey string) ([]byte, error) {
+	key := make([]byte, masterRecoveryKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate master recovery key: %w", err)
+	}
+
+	km, err := NewKeyManager(fsys, encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build key manager: %w", err)
+	}
+
+	ctx := context.Background()
+	ciphertext, keyVersion, err := km.Wrap(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap master recovery key: %w", err)
+	}
+
+	if err := writeWrappedKey(fsys, wrappedKeyPath, wrappedKey{
+		URI:        km.URI(),
+		KeyVersion: keyVersion,
+		Ciphertext: ciphertext,
+	}); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Derives a recovery key from password using deriver, and writes a
+// self-describing header ($<kdf>$<params>$<salt-b64>$<key-b64>) to keyPath so
+// VerifyRecoveryKey and future tooling can reconstruct the derivation without
+// knowing which KDF produced the file in advance.
+func generateRecoveryKeyFromPassword(fsys WritableFS, keyPath, password string, deriver kdf.KeyDeriver) ([]byte, error) {
+	salt := make([]byte, deriver.SaltSize())
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate recovery key salt: %w", err)
+	}
+
+	key, err := deriver.Derive([]byte(password), salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive recovery key: %w", err)
+	}
+
+	header := kdf.Encode(deriver, salt, key)
+	if err := fsys.WriteFile(keyPath, []byte(header), secretFilePerm); err != nil {
+		return nil, fmt.Errorf("failed to write recovery key: %w", err)
+	}
+
+	return key, nil
+}
+
+// writeRecoveryKeyMnemonic encodes key as a BIP39 mnemonic and writes it to
+// recovery.mnemonic inside dir with 0400 permissions, so operators have a
+// human-transcribable alternative to the header/wrapped recovery key.
+func writeRecoveryKeyMnemonic(fsys WritableFS, dir string, key []byte) error {
+	words, err := recoveryKeyToMnemonic(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode recovery key as mnemonic: %w", err)
+	}
+	mnemonicPath := filepath.Join(dir, "recovery.mnemonic")
+	if err := fsys.WriteFile(mnemonicPath, []byte(strings.Join(words, " ")+"\n"), secretFilePerm); err != nil {
+		return fmt.Errorf("failed to write recovery mnemonic: %w", err)
+	}
+	return nil
+}
+
+// VerifyRecoveryKey reports whether password matches the recovery key stored
+// at keyPath. It understands both the current self-describing header format
+// and the legacy repeated-SHA-256 format used before the kdf package existed,
+// so recovery keys generated by older versions of RenderTridentHostConfig
+// remain verifiable.
+func VerifyRecoveryKey(keyPath, password string) (bool, error) {
+	content, err := OSFS{}.ReadFile(keyPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read recovery key: %w", err)
+	}
+
+	// Every Encode-produced header is longer than a legacy key, so a
+	// legacy-length file whose first byte happens to be '$' is still treated
+	// as a legacy key.
+	if kdf.IsHeader(content) && len(content) != legacyRecoveryKeyLength {
+		header, err := kdf.Parse(string(content))
+		if err != nil {
+			return false, fmt.Errorf("failed to parse recovery key header: %w", err)
+		}
+		deriver, err := kdf.ByName(header.Name)
+		if err != nil {
+			return false, fmt.Errorf("failed to verify recovery key: %w", err)
+		}
+		derived, err := deriver.Derive([]byte(password), header.Salt)
+		if err != nil {
+			return false, fmt.Errorf("failed to derive recovery key: %w", err)
+		}
+		return subtle.ConstantTimeCompare(derived, header.Key) == 1, nil
+	}
+
+	// Legacy format: a bare 64-byte key produced by the pre-kdf repeated
+	// SHA-256 construction, with no salt or parameters on disk.
+	legacy := legacyDeriveRecoveryKey(password)
+	return subtle.ConstantTimeCompare(legacy, content) == 1, nil
+}
+
+// legacyRecoveryKeyLength is the size of a legacy raw-binary recovery key.
+const legacyRecoveryKeyLength = 64
+
+// legacyDeriveRecoveryKey reproduces the original ad-hoc key stretching
+// construction (fixed salt, repeated SHA-256) solely so recovery keys written
+// before the kdf package existed can still be verified.
+func legacyDeriveRecoveryKey(password string) []byte {
 	salt := []byte("trident_recovery_salt_v1")
-	iterations := 100000
-	keyLength := 64
+	const iterations = 100000
+	const keyLength = legacyRecoveryKeyLength
 
-	// Simple PBKDF2-like implementation using repeated hashing
 	key := make([]byte, keyLength)
 	current := sha256.Sum256(append([]byte(password), salt...))
 
-	// Multiple rounds for key stretching
 	for i := 0; i < iterations; i++ {
 		h := sha256.New()
 		h.Write(current[:])
@@ -97,11 +374,9 @@ func generateRecoveryKeyFromPassword(keyPath, password string) error {
 		current = [32]byte(h.Sum(nil))
 	}
 
-	// Expand to 64 bytes
 	for i := 0; i < keyLength; i += 32 {
 		copy(key[i:], current[:])
 		if i+32 < keyLength {
-			// Generate next 32 bytes
 			h := sha256.New()
 			h.Write(current[:])
 			h.Write([]byte{byte(i / 32)})
@@ -109,10 +384,5 @@ func generateRecoveryKeyFromPassword(keyPath, password string) error {
 		}
 	}
 
-	// Write key to file with proper permissions
-	if err := os.WriteFile(keyPath, key, 0400); err != nil {
-		return fmt.Errorf("failed to write recovery key: %w", err)
-	}
-
-	return nil
+	return key
 }
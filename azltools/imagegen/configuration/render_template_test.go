@@ -0,0 +1,185 @@
+package configuration
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var errInjectedWriteFailure = errors.New("injected write failure")
+
+func TestRenderTridentHostConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		configData *TridentConfigData
+		wantFiles  []string
+	}{
+		{
+			name: "unencrypted",
+			configData: &TridentConfigData{
+				Username: "testuser",
+				Password: "hunter2",
+			},
+			wantFiles: []string{
+				"/out/host-config/scripts/user-password.sh",
+				"/out/host-config/host-config.yaml",
+			},
+		},
+		{
+			name: "password-derived recovery key",
+			configData: &TridentConfigData{
+				Username:      "testuser",
+				Password:      "hunter2",
+				EncryptionKey: "correct-horse-battery-staple",
+			},
+			wantFiles: []string{
+				"/out/host-config/scripts/user-password.sh",
+				"/out/host-config/recovery.key",
+				"/out/host-config/host-config.yaml",
+			},
+		},
+		{
+			name: "password-derived recovery key with mnemonic",
+			configData: &TridentConfigData{
+				Username:            "testuser",
+				Password:            "hunter2",
+				EncryptionKey:       "correct-horse-battery-staple",
+				RecoveryKeyMnemonic: true,
+			},
+			wantFiles: []string{
+				"/out/host-config/scripts/user-password.sh",
+				"/out/host-config/recovery.key",
+				"/out/host-config/recovery.mnemonic",
+				"/out/host-config/host-config.yaml",
+			},
+		},
+		{
+			name: "KMS-wrapped recovery key",
+			configData: &TridentConfigData{
+				Username:      "testuser",
+				Password:      "hunter2",
+				EncryptionKey: "file:///wrapping.key",
+			},
+			wantFiles: []string{
+				"/out/host-config/scripts/user-password.sh",
+				"/out/host-config/recovery.key.wrapped",
+				"/out/host-config/host-config.yaml",
+			},
+		},
+		{
+			name: "in-memory KMS-wrapped recovery key",
+			configData: &TridentConfigData{
+				Username:      "testuser",
+				Password:      "hunter2",
+				EncryptionKey: "mem://test-kms",
+			},
+			wantFiles: []string{
+				"/out/host-config/scripts/user-password.sh",
+				"/out/host-config/recovery.key.wrapped",
+				"/out/host-config/host-config.yaml",
+			},
+		},
+		{
+			name: "age-encrypted output",
+			configData: &TridentConfigData{
+				Username:      "testuser",
+				Password:      "hunter2",
+				AgeRecipients: []string{"a-test-passphrase"},
+			},
+			wantFiles: []string{
+				"/out/host-config/scripts/user-password.sh.age",
+				"/out/host-config/host-config.yaml.age",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS()
+			if strings.HasPrefix(tt.configData.EncryptionKey, "file://") {
+				if err := fsys.WriteFile("/wrapping.key", make([]byte, 32), secretFilePerm); err != nil {
+					t.Fatalf("failed to seed wrapping key: %v", err)
+				}
+			}
+
+			if err := renderTridentHostConfig(fsys, "/out/host-config/host-config.yaml", tt.configData); err != nil {
+				t.Fatalf("renderTridentHostConfig() error = %v", err)
+			}
+
+			for _, path := range tt.wantFiles {
+				if _, err := fsys.ReadFile(path); err != nil {
+					t.Errorf("expected %s to exist: %v", path, err)
+				}
+			}
+
+			if data, err := fsys.ReadFile(tt.configData.PasswordScript); err == nil && strings.Contains(string(data), tt.configData.Password) {
+				t.Errorf("password script must not contain the cleartext password, got %q", data)
+			}
+		})
+	}
+}
+
+func TestRenderTridentHostConfigStagingFailureLeavesNoPartialState(t *testing.T) {
+	tests := []struct {
+		name   string
+		failOn string
+	}{
+		{name: "password script write fails", failOn: "/out/host-config.staging-1/scripts/user-password.sh"},
+		{name: "recovery key write fails", failOn: "/out/host-config.staging-1/recovery.key"},
+		{name: "config file create fails", failOn: "/out/host-config.staging-1/host-config.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS()
+			fsys.FailOn = map[string]error{tt.failOn: errInjectedWriteFailure}
+
+			configData := &TridentConfigData{
+				Username:      "testuser",
+				Password:      "hunter2",
+				EncryptionKey: "correct-horse-battery-staple",
+			}
+
+			err := renderTridentHostConfig(fsys, "/out/host-config/host-config.yaml", configData)
+			if err == nil {
+				t.Fatalf("expected an error from the injected failure at %s", tt.failOn)
+			}
+
+			if _, statErr := fsys.ReadFile("/out/host-config/host-config.yaml"); statErr == nil {
+				t.Errorf("committed config file must not exist after a staging failure")
+			}
+			if len(fsys.files) != 0 {
+				t.Errorf("staging directory should have been removed on failure, found files: %v", fsys.files)
+			}
+		})
+	}
+}
+
+func TestRenderTridentHostConfigReplacesExistingDirectory(t *testing.T) {
+	fsys := NewMemFS()
+	configData := &TridentConfigData{
+		Username: "testuser",
+		Password: "hunter2",
+	}
+
+	if err := renderTridentHostConfig(fsys, "/out/host-config/host-config.yaml", configData); err != nil {
+		t.Fatalf("first renderTridentHostConfig() error = %v", err)
+	}
+
+	configData = &TridentConfigData{
+		Username: "testuser",
+		Password: "swordfish",
+	}
+	if err := renderTridentHostConfig(fsys, "/out/host-config/host-config.yaml", configData); err != nil {
+		t.Fatalf("second renderTridentHostConfig() error = %v", err)
+	}
+
+	if _, err := fsys.ReadFile("/out/host-config/host-config.yaml"); err != nil {
+		t.Errorf("expected host-config.yaml to exist after re-render: %v", err)
+	}
+	for path := range fsys.files {
+		if strings.Contains(path, ".previous") || strings.Contains(path, ".staging-") {
+			t.Errorf("leftover staging/backup path after successful re-render: %s", path)
+		}
+	}
+}
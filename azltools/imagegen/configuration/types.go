@@ -0,0 +1,43 @@
+package configuration
+
+import "trident/azltools/imagegen/configuration/hash"
+
+// TridentConfigData holds the values substituted into the host configuration
+// templates and controls how RenderTridentHostConfig provisions the
+// surrounding artifacts (password script, recovery key, encryption).
+type TridentConfigData struct {
+	Username string
+	Password string
+
+	// EncryptionKey, when set, is either a passphrase from which a recovery
+	// key is derived, or a KMS URI (see IsKMSURI) naming the KeyManager that
+	// wraps a randomly generated master recovery key.
+	EncryptionKey string
+
+	// KDF selects the key derivation algorithm used to derive the recovery
+	// key from EncryptionKey ("pbkdf2-sha256", "scrypt", "argon2id"). Empty
+	// defaults to kdf.Default (scrypt).
+	KDF string
+
+	// RecoveryKeyMnemonic, when true, additionally writes the recovery key
+	// (password-derived or KMS master key) as a BIP39 mnemonic to
+	// recovery.mnemonic, for operators who need a human-transcribable form.
+	RecoveryKeyMnemonic bool
+
+	// AgeRecipients, when set, causes RenderTridentHostConfig to encrypt the
+	// rendered host-config.yaml and user-password.sh with age instead of
+	// writing them in cleartext. Entries may be X25519 public keys
+	// ("age1..."), SSH public keys ("ssh-ed25519 ..."), or scrypt
+	// passphrases.
+	AgeRecipients []string
+
+	// PasswordHasher hashes Password for the generated password script
+	// instead of embedding it in cleartext. Nil defaults to hash.Bcrypt{}
+	// (cost 12).
+	PasswordHasher hash.Hasher
+
+	// PasswordScript and RecoveryKeyPath are populated by
+	// RenderTridentHostConfig and made available to the templates.
+	PasswordScript  string
+	RecoveryKeyPath string
+}